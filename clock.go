@@ -30,6 +30,134 @@ type Clock interface {
 	NewTicker(d time.Duration) Ticker
 }
 
+// ClockExt is implemented by a Clock that also provides the time-package
+// convenience wrappers (After, Sleep, Tick, Since, Until, AfterFunc) as
+// methods of its own, typically because it can implement them more
+// efficiently than the package-level functions of the same name can from
+// Now, NewTimer, and NewTicker alone. Adding these methods to Clock itself
+// would have broken every existing implementation of it outside this
+// module, so they live on this separate interface instead; callers that
+// don't know or care whether their Clock satisfies ClockExt should use the
+// package-level functions, which fall back to synthesizing the same
+// behavior from Clock when it doesn't.
+type ClockExt interface {
+	Clock
+
+	// After waits for the duration to elapse and then sends the current
+	// time on the returned channel, mirroring time.After.
+	After(d time.Duration) <-chan time.Time
+
+	// Sleep pauses the calling goroutine until the duration has elapsed,
+	// mirroring time.Sleep.
+	Sleep(d time.Duration)
+
+	// Tick is a convenience wrapper for NewTicker that returns only the
+	// channel, mirroring time.Tick. Like time.Tick, there is no way to
+	// stop the underlying ticker, so Tick should not be used in code that
+	// creates many tickers; use NewTicker instead.
+	Tick(d time.Duration) <-chan time.Time
+
+	// Since returns the elapsed time since t, mirroring time.Since.
+	Since(t time.Time) time.Duration
+
+	// Until returns the duration until t, mirroring time.Until.
+	Until(t time.Time) time.Duration
+
+	// AfterFunc waits for the duration to elapse and then calls f in its own
+	// goroutine, mirroring time.AfterFunc. It returns a Timer that can be
+	// used to cancel the call using its Stop method. Unlike NewTimer, the
+	// returned Timer's C method returns a nil channel, since f receives the
+	// tick instead of a channel send. As with time.Timer.Stop, Stop does
+	// not wait for f to finish.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// After waits for the duration to elapse and then sends the current time
+// on the returned channel, mirroring time.After. If c implements ClockExt,
+// its After method is used directly; otherwise this is equivalent to
+// c.NewTimer(d).C().
+func After(c Clock, d time.Duration) <-chan time.Time {
+	if ce, ok := c.(ClockExt); ok {
+		return ce.After(d)
+	}
+	return c.NewTimer(d).C()
+}
+
+// Sleep pauses the calling goroutine until the duration has elapsed,
+// mirroring time.Sleep. If c implements ClockExt, its Sleep method is used
+// directly; otherwise this blocks on After(c, d).
+func Sleep(c Clock, d time.Duration) {
+	if ce, ok := c.(ClockExt); ok {
+		ce.Sleep(d)
+		return
+	}
+	<-After(c, d)
+}
+
+// Tick is a convenience wrapper for c.NewTicker that returns only the
+// channel, mirroring time.Tick. Like time.Tick, there is no way to stop
+// the underlying ticker, so Tick should not be used in code that creates
+// many tickers; use c.NewTicker instead. Tick returns nil if d is not
+// positive. If c implements ClockExt, its Tick method is used directly.
+func Tick(c Clock, d time.Duration) <-chan time.Time {
+	if ce, ok := c.(ClockExt); ok {
+		return ce.Tick(d)
+	}
+	if d <= 0 {
+		return nil
+	}
+	return c.NewTicker(d).C()
+}
+
+// Since returns the time elapsed since t, as measured by c, mirroring
+// time.Since. If c implements ClockExt, its Since method is used directly.
+func Since(c Clock, t time.Time) time.Duration {
+	if ce, ok := c.(ClockExt); ok {
+		return ce.Since(t)
+	}
+	return c.Now().Sub(t)
+}
+
+// Until returns the duration until t, as measured by c, mirroring
+// time.Until. If c implements ClockExt, its Until method is used directly.
+func Until(c Clock, t time.Time) time.Duration {
+	if ce, ok := c.(ClockExt); ok {
+		return ce.Until(t)
+	}
+	return t.Sub(c.Now())
+}
+
+// AfterFunc waits for the duration to elapse and then calls f in its own
+// goroutine, mirroring time.AfterFunc. It returns a Timer that can be used
+// to cancel the call using its Stop method. If c implements ClockExt, its
+// AfterFunc method is used directly; otherwise f is called from a goroutine
+// that waits for the tick on the channel of c.NewTimer(d), and the returned
+// Timer's C method returns a nil channel, since f receives the tick
+// instead of a channel send. As with time.Timer.Stop, Stop does not wait
+// for f to finish.
+func AfterFunc(c Clock, d time.Duration, f func()) Timer {
+	if ce, ok := c.(ClockExt); ok {
+		return ce.AfterFunc(d, f)
+	}
+	t := c.NewTimer(d)
+	go func() {
+		<-t.C()
+		f()
+	}()
+	return fallbackAfterFuncTimer{t}
+}
+
+// fallbackAfterFuncTimer wraps the Timer returned by Clock.NewTimer to
+// give AfterFunc's fallback path the nil-channel C behavior that
+// ClockExt.AfterFunc's doc comment promises.
+type fallbackAfterFuncTimer struct {
+	Timer
+}
+
+func (fallbackAfterFuncTimer) C() <-chan time.Time {
+	return nil
+}
+
 // A Timer represents a single event.
 type Timer interface {
 	C() <-chan time.Time
@@ -43,8 +171,8 @@ type Ticker interface {
 	Stop()
 }
 
-// System implements Clock by using the functions in the time package.
-var System Clock = sys{}
+// System implements ClockExt by using the functions in the time package.
+var System ClockExt = sys{}
 
 type sys struct{}
 
@@ -53,6 +181,31 @@ func (sys) Now() time.Time {
 	return time.Now()
 }
 
+// After returns time.After(d).
+func (sys) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Sleep calls time.Sleep(d).
+func (sys) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// Tick returns time.Tick(d).
+func (sys) Tick(d time.Duration) <-chan time.Time {
+	return time.Tick(d)
+}
+
+// Since returns time.Since(t).
+func (sys) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// Until returns time.Until(t).
+func (sys) Until(t time.Time) time.Duration {
+	return time.Until(t)
+}
+
 // NewTimer returns time.NewTimer(d)
 func (sys) NewTimer(d time.Duration) Timer {
 	return sysTimer{time.NewTimer(d)}
@@ -66,6 +219,11 @@ func (t sysTimer) C() <-chan time.Time {
 	return t.Timer.C
 }
 
+// AfterFunc returns time.AfterFunc(d, f).
+func (sys) AfterFunc(d time.Duration, f func()) Timer {
+	return sysTimer{time.AfterFunc(d, f)}
+}
+
 // NewTicker returns time.NewTicker(d)
 func (sys) NewTicker(d time.Duration) Ticker {
 	return sysTicker{time.NewTicker(d)}
@@ -26,11 +26,49 @@ import (
 
 // Clock implements clock.Clock by maintaining its own time.
 type Clock struct {
-	step time.Duration
+	step     time.Duration
+	follow   bool
+	chanSize int
+
+	m        sync.Mutex
+	cond     sync.Cond
+	state    state
+	watch    func(time.Duration)
+	lastReal time.Time
+}
 
-	m     sync.Mutex
-	state state
-	watch func(time.Duration)
+// Options holds the parameters for NewWithOptions.
+type Options struct {
+	// Step is added to the clock's time on every call to Now, as with NewWithStep.
+	Step time.Duration
+
+	// FollowRealTime causes the clock to advance its time by the wall-clock
+	// time elapsed between operations, in addition to any jumps made with
+	// Add. This lets tests control a base time and specific jumps while
+	// long-running background goroutines still observe natural time
+	// progression.
+	FollowRealTime bool
+
+	// TimerChannelSize sets the buffer size of the channels returned by
+	// Timer.C and Ticker.C. It defaults to 1, the size used by the time
+	// package, and must not be negative.
+	TimerChannelSize int
+
+	// WheelResolution is the tick granularity of the hierarchical timing
+	// wheel used to schedule timers and tickers. It defaults to 1
+	// millisecond and must not be negative. Add advances the wheel in
+	// units of this size; any remainder is carried over to the next Add.
+	// Timers and tickers fire only on these tick boundaries, so a deadline
+	// that falls between two ticks fires once the clock reaches the next one.
+	WheelResolution time.Duration
+
+	// WheelSize is the number of buckets per level of the timing wheel.
+	// It defaults to 512 and must not be negative.
+	WheelSize int
+
+	// WheelLevels is the number of cascaded levels in the timing wheel.
+	// It defaults to 4 and must not be negative.
+	WheelLevels int
 }
 
 // New returns a new fake clock initialized to time t.
@@ -41,24 +79,84 @@ func New(t time.Time) *Clock {
 // NewWithStep returns a new fake clock that increases in time with each call to Now.
 // It panics if step is negative.
 func NewWithStep(start time.Time, step time.Duration) *Clock {
-	if step < 0 {
-		panic("fakeclock: NewWithStep with negative step")
+	return NewWithOptions(start, Options{Step: step})
+}
+
+// NewWithOptions returns a new fake clock initialized to time start,
+// configured by opts. It panics if opts.Step, opts.TimerChannelSize,
+// opts.WheelResolution, opts.WheelSize, or opts.WheelLevels is negative.
+func NewWithOptions(start time.Time, opts Options) *Clock {
+	if opts.Step < 0 {
+		panic("fakeclock: NewWithOptions with negative Step")
+	}
+	if opts.TimerChannelSize < 0 {
+		panic("fakeclock: NewWithOptions with negative TimerChannelSize")
+	}
+	if opts.WheelResolution < 0 {
+		panic("fakeclock: NewWithOptions with negative WheelResolution")
+	}
+	if opts.WheelSize < 0 {
+		panic("fakeclock: NewWithOptions with negative WheelSize")
+	}
+	if opts.WheelLevels < 0 {
+		panic("fakeclock: NewWithOptions with negative WheelLevels")
+	}
+	chanSize := opts.TimerChannelSize
+	if chanSize == 0 {
+		chanSize = 1
+	}
+	resolution := opts.WheelResolution
+	if resolution == 0 {
+		resolution = defaultWheelResolution
+	}
+	size := opts.WheelSize
+	if size == 0 {
+		size = defaultWheelSize
 	}
-	return &Clock{
-		state: state{t: start},
-		step:  step,
+	levels := opts.WheelLevels
+	if levels == 0 {
+		levels = defaultWheelLevels
 	}
+	clock := &Clock{
+		state:    state{t: start, wheel: newWheel(start, resolution, size, levels)},
+		step:     opts.Step,
+		follow:   opts.FollowRealTime,
+		chanSize: chanSize,
+	}
+	clock.cond.L = &clock.m
+	if clock.follow {
+		clock.lastReal = time.Now()
+	}
+	return clock
+}
+
+// followRealTime advances s.t by the wall-clock time elapsed since the last
+// call, if the clock was created with FollowRealTime.
+func (clock *Clock) followRealTime(s *state) {
+	if !clock.follow {
+		return
+	}
+	now := time.Now()
+	if elapsed := now.Sub(clock.lastReal); elapsed > 0 {
+		s.t = s.t.Add(elapsed)
+	}
+	clock.lastReal = now
 }
 
 func (clock *Clock) do(f func(*state)) {
 	clock.m.Lock()
 	s := &clock.state
-	t, w := s.t, clock.watch
+	t := s.t
+	clock.followRealTime(s)
+	w := clock.watch
+	n := s.wheel.count
 	f(s)
 	var args []time.Duration
 	if s.t != t {
-		s.notifyTimers()
-		args = s.notifyTickers()
+		args = s.wheel.advance(s.t)
+	}
+	if s.wheel.count != n {
+		clock.cond.Broadcast()
 	}
 	clock.m.Unlock()
 
@@ -71,12 +169,48 @@ func (clock *Clock) do(f func(*state)) {
 
 func (clock *Clock) newWatcher(d time.Duration, f func(*state)) {
 	clock.m.Lock()
+	s := &clock.state
+	t := s.t
+	clock.followRealTime(s)
 	w := clock.watch
-	f(&clock.state)
+	n := s.wheel.count
+	f(s)
+	var args []time.Duration
+	if s.t != t {
+		args = s.wheel.advance(s.t)
+	}
+	if s.wheel.count != n {
+		clock.cond.Broadcast()
+	}
 	clock.m.Unlock()
 
 	if w != nil {
 		w(d)
+		for _, period := range args {
+			w(period)
+		}
+	}
+}
+
+// NumWaiters returns the number of pending timers and tickers.
+func (clock *Clock) NumWaiters() int {
+	clock.m.Lock()
+	n := clock.state.wheel.count
+	clock.m.Unlock()
+	return n
+}
+
+// HasWaiters reports whether there are any pending timers or tickers.
+func (clock *Clock) HasWaiters() bool {
+	return clock.NumWaiters() > 0
+}
+
+// BlockUntil blocks until there are at least n pending timers and tickers.
+func (clock *Clock) BlockUntil(n int) {
+	clock.m.Lock()
+	defer clock.m.Unlock()
+	for clock.state.wheel.count < n {
+		clock.cond.Wait()
 	}
 }
 
@@ -116,10 +250,29 @@ func (clock *Clock) NewTimer(d time.Duration) clock.Timer {
 	clock.newWatcher(d, func(s *state) {
 		t = &timer{
 			clock: clock,
-			c:     make(chan time.Time, 1),
+			c:     make(chan time.Time, clock.chanSize),
+		}
+		if t.init(s.t, d) {
+			s.wheel.insert(t)
+		}
+	})
+	return t
+}
+
+// AfterFunc creates a timer that will call f in its own goroutine once the
+// clock has advanced by d or more, and returns a Timer that can be used to
+// cancel the call with Stop. Unlike NewTimer, the returned Timer's C method
+// returns a nil channel, since f receives the tick instead of a channel send.
+// As with time.Timer.Stop, Stop does not wait for f to finish.
+func (clock *Clock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	var t *timer
+	clock.newWatcher(d, func(s *state) {
+		t = &timer{
+			clock: clock,
+			f:     f,
 		}
 		if t.init(s.t, d) {
-			s.timers = append(s.timers, t)
+			s.wheel.insert(t)
 		}
 	})
 	return t
@@ -137,15 +290,47 @@ func (clock *Clock) NewTicker(d time.Duration) clock.Ticker {
 	clock.newWatcher(d, func(s *state) {
 		t = &ticker{
 			clock: clock,
-			c:     make(chan time.Time, 1),
+			c:     make(chan time.Time, clock.chanSize),
 			d:     d,
 			next:  s.t.Add(d),
 		}
-		s.tickers = append(s.tickers, t)
+		s.wheel.insert(t)
 	})
 	return t
 }
 
+// After returns a channel that will receive the clock's time once it has
+// advanced by d or more. It is equivalent to clock.NewTimer(d).C().
+func (clock *Clock) After(d time.Duration) <-chan time.Time {
+	return clock.NewTimer(d).C()
+}
+
+// Sleep blocks until the clock has advanced by d or more.
+func (clock *Clock) Sleep(d time.Duration) {
+	<-clock.After(d)
+}
+
+// Tick is a convenience wrapper for NewTicker that returns only the channel.
+// Like time.Tick, there is no way to stop the underlying ticker, so Tick
+// should not be used in code that creates many tickers; use NewTicker
+// directly in that case. Tick returns nil if d is not positive.
+func (clock *Clock) Tick(d time.Duration) <-chan time.Time {
+	if d <= 0 {
+		return nil
+	}
+	return clock.NewTicker(d).C()
+}
+
+// Since returns the time elapsed since t, as measured by the clock.
+func (clock *Clock) Since(t time.Time) time.Duration {
+	return clock.Now().Sub(t)
+}
+
+// Until returns the duration until t, as measured by the clock.
+func (clock *Clock) Until(t time.Time) time.Duration {
+	return t.Sub(clock.Now())
+}
+
 // SetWatchFunc sets the watch callback to f, which may be nil.
 // f will be called when a timer is created, when a timer is reset, when
 // a ticker is created, and after a ticker fires.
@@ -158,21 +343,38 @@ func (clock *Clock) SetWatchFunc(f func(d time.Duration)) {
 type timer struct {
 	clock *Clock
 	c     chan time.Time
+	f     func() // set for timers created by AfterFunc; c is nil in that case
 
 	time  time.Time
 	fired bool
+	wnode wheelNode
 }
 
 func (t *timer) C() <-chan time.Time {
 	return t.c
 }
 
+// Reset changes the timer to expire after duration d, as though the timer
+// was just created. It returns true if the timer had been active, false if
+// the timer had expired or been stopped.
+//
+// As with time.Timer.Reset, Reset should be invoked only on stopped or
+// expired timers with drained channels; to make that safe even when the
+// caller hasn't read a fired-but-undelivered value off t.C(), Reset drains
+// it for them before rescheduling, so a stale value from the previous
+// period is never confused with the new one.
 func (t *timer) Reset(d time.Duration) bool {
 	var active bool
 	t.clock.newWatcher(d, func(s *state) {
 		active = !t.fired
+		if !active && t.c != nil {
+			select {
+			case <-t.c:
+			default:
+			}
+		}
 		if t.init(s.t, d) {
-			s.addTimer(t)
+			s.rescheduleTimer(t)
 		} else {
 			s.removeTimer(t)
 		}
@@ -180,6 +382,16 @@ func (t *timer) Reset(d time.Duration) bool {
 	return active
 }
 
+// Stop prevents the Timer from firing. It returns true if the call stops
+// the timer, false if the timer has already expired or been stopped.
+// Stop does not drain t.C(), so a value sent before the call stays
+// readable: as with time.Timer.Stop, a caller that has not already
+// received from t.C() should follow a false return with a drain, e.g.
+// "if !t.Stop() { <-t.C() }".
+//
+// For a Timer created with AfterFunc, if Stop returns false, then the
+// timer has already expired and its function has been started in its own
+// goroutine; Stop does not wait for it to finish before returning.
 func (t *timer) Stop() bool {
 	var active bool
 	t.clock.do(func(s *state) {
@@ -190,8 +402,14 @@ func (t *timer) Stop() bool {
 	return active
 }
 
-func (t *timer) update(now time.Time) (done bool) {
-	if t.fired || now.Before(t.time) {
+func (t *timer) wheelDeadline() time.Time { return t.time }
+func (t *timer) node() *wheelNode         { return &t.wnode }
+
+// wheelFire fires the timer and reports whether this call was the one that
+// did so. Timers are one-shot and are never reinserted into the wheel
+// after this is called.
+func (t *timer) wheelFire(now time.Time) (fired bool) {
+	if t.fired {
 		return false
 	}
 	t.fire(now)
@@ -199,9 +417,13 @@ func (t *timer) update(now time.Time) (done bool) {
 }
 
 func (t *timer) fire(now time.Time) {
-	select {
-	case t.c <- now:
-	default:
+	if t.f != nil {
+		go t.f()
+	} else {
+		select {
+		case t.c <- now:
+		default:
+		}
 	}
 	t.fired = true
 }
@@ -221,8 +443,9 @@ type ticker struct {
 	clock *Clock
 	c     chan time.Time
 
-	d    time.Duration
-	next time.Time
+	d     time.Duration
+	next  time.Time
+	wnode wheelNode
 }
 
 func (t *ticker) C() <-chan time.Time {
@@ -235,9 +458,15 @@ func (t *ticker) Stop() {
 	})
 }
 
-func (t *ticker) update(now time.Time) time.Duration {
+func (t *ticker) wheelDeadline() time.Time { return t.next }
+func (t *ticker) node() *wheelNode         { return &t.wnode }
+
+// wheelFire sends a tick if the ticker is due and advances it to its next
+// deadline, reporting whether it actually fired. The ticker is always
+// reinserted into the wheel by the caller, regardless of the result.
+func (t *ticker) wheelFire(now time.Time) (fired bool) {
 	if now.Before(t.next) {
-		return 0
+		return false
 	}
 	select {
 	case t.c <- now:
@@ -246,65 +475,27 @@ func (t *ticker) update(now time.Time) time.Duration {
 	for !now.Before(t.next) {
 		t.next = t.next.Add(t.d)
 	}
-	return t.d
+	return true
 }
 
 type state struct {
-	t       time.Time
-	timers  []*timer
-	tickers []*ticker
-}
-
-func (s *state) notifyTimers() {
-	nleft := 0
-	for _, t := range s.timers {
-		done := t.update(s.t)
-		if !done {
-			s.timers[nleft] = t
-			nleft++
-		}
-	}
-	s.timers = s.timers[:nleft]
+	t     time.Time
+	wheel *wheel
 }
 
-func (s *state) notifyTickers() (watches []time.Duration) {
-	watches = make([]time.Duration, 0, len(s.tickers))
-	for _, t := range s.tickers {
-		d := t.update(s.t)
-		if d != 0 {
-			watches = append(watches, d)
-		}
-	}
-	return
-}
-
-func (s *state) addTimer(t *timer) {
-	for _, u := range s.timers {
-		if u == t {
-			return
-		}
-	}
-	s.timers = append(s.timers, t)
+// rescheduleTimer (re-)inserts t into the wheel at the bucket matching its
+// current deadline, removing it from its old bucket first if needed. It is
+// used by Timer.Reset, which may change an already-scheduled timer's
+// deadline.
+func (s *state) rescheduleTimer(t *timer) {
+	s.wheel.remove(t)
+	s.wheel.insert(t)
 }
 
 func (s *state) removeTimer(t *timer) {
-	for i, u := range s.timers {
-		if u == t {
-			copy(s.timers[i:], s.timers[i+1:])
-			n := len(s.timers)
-			s.timers[n-1] = nil
-			s.timers = s.timers[:n-1]
-		}
-	}
+	s.wheel.remove(t)
 }
 
 func (s *state) removeTicker(t *ticker) {
-	for i, u := range s.tickers {
-		if u == t {
-			copy(s.tickers[i:], s.tickers[i+1:])
-			n := len(s.tickers)
-			s.tickers[n-1] = nil
-			s.tickers = s.tickers[:n-1]
-		}
-	}
+	s.wheel.remove(t)
 }
@@ -1,6 +1,7 @@
 package fakeclock
 
 import (
+	"sync"
 	"testing"
 	"time"
 )
@@ -358,6 +359,305 @@ func TestClock_NewTickerCallsWatchFunc(t *testing.T) {
 	}
 }
 
+func TestClock_ManyTimersAllFire(t *testing.T) {
+	const n = 5000
+	clock := New(baseTime)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		clock.AfterFunc(time.Duration(i+1)*time.Millisecond, wg.Done)
+	}
+	clock.BlockUntil(n)
+
+	clock.Add(n * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("not all timers fired in time")
+	}
+}
+
+func TestClock_TimerCascadesAcrossLevels(t *testing.T) {
+	clock := NewWithOptions(baseTime, Options{
+		WheelResolution: 1 * time.Millisecond,
+		WheelSize:       4,
+		WheelLevels:     3,
+	})
+	const d = 100 * time.Millisecond
+	timer := clock.NewTimer(d)
+
+	clock.Add(d)
+	select {
+	case fireTime := <-timer.C():
+		if want := baseTime.Add(d); !fireTime.Equal(want) {
+			t.Errorf("<-timer.C() = %v; want %v", fireTime, want)
+		}
+	default:
+		t.Error("<-timer.C() has nothing after cascading through wheel levels")
+	}
+}
+
+func TestClock_SubResolutionTimerFiresOnNextTick(t *testing.T) {
+	const resolution = 1 * time.Millisecond
+	clock := NewWithOptions(baseTime, Options{WheelResolution: resolution})
+	timer := clock.NewTimer(500 * time.Microsecond)
+
+	clock.Add(resolution)
+	select {
+	case fireTime := <-timer.C():
+		if want := baseTime.Add(resolution); !fireTime.Equal(want) {
+			t.Errorf("<-timer.C() = %v; want %v", fireTime, want)
+		}
+	default:
+		t.Error("sub-resolution timer did not fire on the very next tick")
+	}
+}
+
+func TestClock_SubResolutionTickerFiresOnNextTick(t *testing.T) {
+	const resolution = 1 * time.Millisecond
+	clock := NewWithOptions(baseTime, Options{WheelResolution: resolution})
+	ticker := clock.NewTicker(500 * time.Microsecond)
+
+	clock.Add(resolution)
+	select {
+	case <-ticker.C():
+	default:
+		t.Error("sub-resolution ticker did not fire on the very next tick")
+	}
+}
+
+func TestClock_AccumulatedSubResolutionAddsDoNotFireEarly(t *testing.T) {
+	const (
+		resolution = 1 * time.Millisecond
+		step       = 600 * time.Microsecond
+		deadline   = 1500 * time.Microsecond
+	)
+	clock := NewWithOptions(baseTime, Options{WheelResolution: resolution})
+	timer := clock.NewTimer(deadline)
+
+	clock.Add(step)
+	select {
+	case fireTime := <-timer.C():
+		t.Errorf("<-timer.C() fired at %v after only %v elapsed; want no fire before %v", fireTime, step, deadline)
+	default:
+		// expected: only one step (600us) has elapsed, short of the 1.5ms deadline
+	}
+
+	clock.Add(step)
+	select {
+	case fireTime := <-timer.C():
+		t.Errorf("<-timer.C() fired at %v after only %v elapsed; want no fire before %v", fireTime, 2*step, deadline)
+	default:
+		// expected: two steps (1.2ms total) still haven't reached the 1.5ms deadline
+	}
+}
+
+func TestClock_AfterFuncCallsFOnAdd(t *testing.T) {
+	const d = 50 * time.Millisecond
+	clock := New(baseTime)
+	called := make(chan struct{})
+
+	timer := clock.AfterFunc(d, func() {
+		close(called)
+	})
+	if timer.C() != nil {
+		t.Error("AfterFunc Timer.C() is not nil; want nil")
+	}
+
+	clock.Add(d)
+	select {
+	case <-called:
+	case <-time.After(1 * time.Second):
+		t.Error("AfterFunc callback was not called after Add")
+	}
+}
+
+func TestClock_AfterFuncStopPreventsCall(t *testing.T) {
+	const d = 50 * time.Millisecond
+	clock := New(baseTime)
+	called := make(chan struct{})
+
+	timer := clock.AfterFunc(d, func() {
+		close(called)
+	})
+	timer.Stop()
+	clock.Add(d)
+
+	select {
+	case <-called:
+		t.Error("AfterFunc callback was called after Stop")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestClock_AfterFiresOnAdd(t *testing.T) {
+	const d = 50 * time.Millisecond
+	endTime := baseTime.Add(d)
+	clock := New(baseTime)
+	ch := clock.After(d)
+
+	clock.Add(d)
+	select {
+	case fireTime := <-ch:
+		if !fireTime.Equal(endTime) {
+			t.Errorf("<-clock.After(d) = %v; want %v", fireTime, endTime)
+		}
+	default:
+		t.Error("<-clock.After(d) has nothing")
+	}
+}
+
+func TestClock_SleepBlocksUntilAdd(t *testing.T) {
+	const d = 50 * time.Millisecond
+	clock := New(baseTime)
+	done := make(chan struct{})
+
+	go func() {
+		clock.Sleep(d)
+		close(done)
+	}()
+	clock.BlockUntil(1)
+
+	select {
+	case <-done:
+		t.Fatal("clock.Sleep(d) returned before clock.Add(d)")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Add(d)
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("clock.Sleep(d) did not return after clock.Add(d)")
+	}
+}
+
+func TestClock_TickFiresAfterPeriod(t *testing.T) {
+	const tick = 25 * time.Millisecond
+	clock := New(baseTime)
+	ch := clock.Tick(tick)
+
+	clock.Add(tick)
+	if got := <-ch; !got.Equal(baseTime.Add(tick)) {
+		t.Errorf("<-clock.Tick(d) = %v; want %v", got, baseTime.Add(tick))
+	}
+}
+
+func TestClock_SinceAndUntil(t *testing.T) {
+	const delta = 1 * time.Minute
+	clock := New(baseTime)
+
+	clock.Add(delta)
+	if got := clock.Since(baseTime); got != delta {
+		t.Errorf("clock.Since(baseTime) = %v; want %v", got, delta)
+	}
+	if got := clock.Until(baseTime); got != -delta {
+		t.Errorf("clock.Until(baseTime) = %v; want %v", got, -delta)
+	}
+}
+
+func TestClock_FollowRealTimeAdvances(t *testing.T) {
+	clock := NewWithOptions(baseTime, Options{FollowRealTime: true})
+
+	now1 := clock.Now()
+	time.Sleep(20 * time.Millisecond)
+	now2 := clock.Now()
+
+	if now1.Before(baseTime) {
+		t.Errorf("1st clock.Now() call = %v; want at or after %v", now1, baseTime)
+	}
+	if !now2.After(now1) {
+		t.Errorf("2nd clock.Now() call = %v; want after %v", now2, now1)
+	}
+}
+
+func TestClock_FollowRealTimeHonorsAdd(t *testing.T) {
+	const delta = 1 * time.Hour
+	clock := NewWithOptions(baseTime, Options{FollowRealTime: true})
+
+	clock.Add(delta)
+	now := clock.Peek()
+
+	if !now.After(baseTime.Add(delta - 1)) {
+		t.Errorf("clock.Peek() = %v; want at least %v", now, baseTime.Add(delta))
+	}
+}
+
+func TestClock_FollowRealTimeFiresTimer(t *testing.T) {
+	clock := NewWithOptions(baseTime, Options{FollowRealTime: true})
+	timer := clock.NewTimer(10 * time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	clock.Now()
+
+	select {
+	case <-timer.C():
+	default:
+		t.Error("timer did not fire from real-time progression alone")
+	}
+}
+
+func TestClock_NumWaiters(t *testing.T) {
+	clock := New(baseTime)
+
+	if n := clock.NumWaiters(); n != 0 {
+		t.Errorf("NumWaiters() = %d; want 0", n)
+	}
+	if clock.HasWaiters() {
+		t.Error("HasWaiters() = true; want false")
+	}
+
+	timer := clock.NewTimer(1 * time.Second)
+	if n := clock.NumWaiters(); n != 1 {
+		t.Errorf("NumWaiters() = %d; want 1", n)
+	}
+	if !clock.HasWaiters() {
+		t.Error("HasWaiters() = false; want true")
+	}
+
+	ticker := clock.NewTicker(1 * time.Second)
+	if n := clock.NumWaiters(); n != 2 {
+		t.Errorf("NumWaiters() = %d; want 2", n)
+	}
+
+	timer.Stop()
+	ticker.Stop()
+	if n := clock.NumWaiters(); n != 0 {
+		t.Errorf("NumWaiters() = %d; want 0", n)
+	}
+}
+
+func TestClock_BlockUntil(t *testing.T) {
+	clock := New(baseTime)
+	done := make(chan struct{})
+
+	go func() {
+		clock.BlockUntil(2)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("BlockUntil(2) returned before 2 waiters were registered")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.NewTimer(1 * time.Second)
+	clock.NewTicker(1 * time.Second)
+
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("BlockUntil(2) did not return after 2 waiters were registered")
+	}
+}
+
 func TestTicker_FireCallsWatchFunc(t *testing.T) {
 	const tick = 42 * time.Second
 	clock := New(baseTime)
@@ -376,3 +676,72 @@ func TestTicker_FireCallsWatchFunc(t *testing.T) {
 		t.Error("Ticker fire did not call watch func")
 	}
 }
+
+func TestTimer_ResetAfterFireBeforeReadDropsStaleValue(t *testing.T) {
+	const (
+		d0 = 10 * time.Millisecond
+		d1 = 20 * time.Millisecond
+	)
+	clock := New(baseTime)
+	timer := clock.NewTimer(d0)
+	clock.Add(d0)
+
+	if active := timer.Reset(d1); active {
+		t.Error("Reset() = true after timer already fired; want false")
+	}
+
+	clock.Add(d1)
+	select {
+	case fireTime := <-timer.C():
+		if want := baseTime.Add(d0 + d1); !fireTime.Equal(want) {
+			t.Errorf("<-timer.C() = %v; want %v", fireTime, want)
+		}
+	default:
+		t.Error("<-timer.C() never fired after reset; stale value was left undrained")
+	}
+}
+
+func TestTimer_StopAfterFireBeforeRead(t *testing.T) {
+	const d0 = 10 * time.Millisecond
+	clock := New(baseTime)
+	timer := clock.NewTimer(d0)
+	clock.Add(d0)
+
+	if active := timer.Stop(); active {
+		t.Error("Stop() = true after timer already fired; want false")
+	}
+
+	select {
+	case fireTime := <-timer.C():
+		if want := baseTime.Add(d0); !fireTime.Equal(want) {
+			t.Errorf("<-timer.C() = %v; want %v", fireTime, want)
+		}
+	default:
+		t.Error("<-timer.C() did not still hold the fired value after Stop")
+	}
+}
+
+func TestTimer_ResetRaceWithAdd(t *testing.T) {
+	const d0 = 10 * time.Millisecond
+	clock := New(baseTime)
+	timer := clock.NewTimer(d0)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		clock.Add(d0)
+	}()
+	go func() {
+		defer wg.Done()
+		timer.Reset(d0)
+	}()
+	wg.Wait()
+
+	clock.Add(2 * d0)
+	select {
+	case <-timer.C():
+	case <-time.After(1 * time.Second):
+		t.Fatal("timer never fired after concurrent Reset and Add")
+	}
+}
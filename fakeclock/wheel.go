@@ -0,0 +1,259 @@
+/*
+	Copyright 2014 Google Inc. All rights reserved.
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package fakeclock
+
+import (
+	"container/list"
+	"time"
+)
+
+// Default parameters for the hierarchical timing wheel used to schedule
+// timers and tickers. The defaults bound memory to size*levels buckets
+// while keeping the number of cascades needed to reach a distant deadline
+// small.
+const (
+	defaultWheelResolution = 1 * time.Millisecond
+	defaultWheelSize       = 512
+	defaultWheelLevels     = 4
+)
+
+// wheelEntry is implemented by *timer and *ticker, the two kinds of
+// deadlines the wheel schedules.
+type wheelEntry interface {
+	// wheelDeadline returns the absolute time the entry should next be
+	// examined.
+	wheelDeadline() time.Time
+
+	// wheelFire is called once the wheel's cursor reaches the entry's
+	// deadline and reports whether this call actually fired it. Whether
+	// the entry is reinserted into the wheel afterwards is up to the
+	// caller, not this return value: timers are one-shot and never
+	// reinserted, while tickers always are.
+	wheelFire(now time.Time) (fired bool)
+
+	// node returns the entry's back-pointer into the wheel, used for O(1)
+	// removal.
+	node() *wheelNode
+}
+
+// wheelNode is embedded in *timer and *ticker to give the wheel an O(1)
+// back-pointer for removal, instead of scanning a bucket to find it.
+type wheelNode struct {
+	inserted    bool
+	level, slot int
+	elem        *list.Element
+}
+
+// wheel is a hierarchical timing wheel, the design used by the Linux
+// kernel and similar timer subsystems (Varghese & Lauck, "Hashed and
+// Hierarchical Timing Wheels", 1996), adapted to schedule fakeclock timers
+// and tickers. Entries are bucketed by how many resolution-sized ticks
+// remain until their deadline; an entry too far in the future is held in a
+// coarser level and cascades into finer levels as the wheel catches up to
+// it. Insertion, removal, and per-tick processing are all O(1) amortized
+// (cascades aside), so Add no longer needs to scan every pending entry.
+type wheel struct {
+	resolution time.Duration
+	size       int
+	count      int
+
+	now     time.Time     // the wheel's own clock: start plus a whole number of resolutions
+	cursor  []int         // current bucket index at each level
+	buckets [][]list.List // buckets[level][slot]
+}
+
+func newWheel(start time.Time, resolution time.Duration, size, levels int) *wheel {
+	buckets := make([][]list.List, levels)
+	for i := range buckets {
+		buckets[i] = make([]list.List, size)
+	}
+	return &wheel{
+		resolution: resolution,
+		size:       size,
+		now:        start,
+		cursor:     make([]int, levels),
+		buckets:    buckets,
+	}
+}
+
+// insert adds e to the wheel, choosing its bucket from e.wheelDeadline.
+// tick advances the cursor and drains level 0's bucket before any of this
+// runs, so the bucket 0 ticks ahead of the cursor is one already drained
+// and won't be visited again for a full rotation; a deadline that rounds
+// down to 0 ticks is clamped to 1 so it lands in the bucket the very next
+// tick drains instead of being missed.
+func (w *wheel) insert(e wheelEntry) {
+	ticks := w.ticksUntil(e.wheelDeadline())
+	if ticks < 1 {
+		ticks = 1
+	}
+	w.insertAt(e, ticks)
+}
+
+// cascade reinserts e, which tick just drained from a higher level's
+// bucket in order to reclassify it into the bucket its deadline now falls
+// into. Unlike insert, it must not clamp a 0-tick result up to 1: level
+// 0's bucket for the current tick has not been drained yet when tick
+// calls this, so an entry that lands there is still caught later in the
+// very same tick instead of having to wait a full rotation.
+func (w *wheel) cascade(e wheelEntry) {
+	w.insertAt(e, w.ticksUntil(e.wheelDeadline()))
+}
+
+func (w *wheel) insertAt(e wheelEntry, ticks int64) {
+	n := e.node()
+	n.level, n.slot = w.locate(ticks)
+	n.elem = w.buckets[n.level][n.slot].PushBack(e)
+	n.inserted = true
+	w.count++
+}
+
+// remove removes e from the wheel. It is a no-op if e is not inserted.
+func (w *wheel) remove(e wheelEntry) {
+	n := e.node()
+	if !n.inserted {
+		return
+	}
+	w.buckets[n.level][n.slot].Remove(n.elem)
+	n.inserted = false
+	n.elem = nil
+	w.count--
+}
+
+// ticksUntil returns the number of resolution-sized ticks between w.now and
+// deadline, rounded up so a deadline that falls between two ticks is
+// never reached before it's due -- flooring instead would fire entries
+// whose deadline isn't a whole multiple of the resolution up to one tick
+// early.
+func (w *wheel) ticksUntil(deadline time.Time) int64 {
+	d := deadline.Sub(w.now)
+	if d <= 0 {
+		return 0
+	}
+	ticks := int64(d / w.resolution)
+	if d%w.resolution != 0 {
+		ticks++
+	}
+	return ticks
+}
+
+// locate returns the bucket that an entry ticks ahead of w.now belongs in:
+// the lowest level whose span can represent ticks, with the furthest
+// bucket of the top level used as a clamp for deadlines beyond the wheel's
+// total range. Clamped entries are rescheduled correctly as they cascade
+// down on subsequent calls.
+func (w *wheel) locate(ticks int64) (level, slot int) {
+	stride := int64(1)
+	top := len(w.cursor) - 1
+	for level = 0; level < top; level++ {
+		span := stride * int64(w.size)
+		if ticks < span {
+			break
+		}
+		stride = span
+	}
+	if level == top {
+		if max := stride*int64(w.size) - 1; ticks > max {
+			ticks = max
+		}
+	}
+	slot = (w.cursor[level] + int(ticks/stride)) % w.size
+	return level, slot
+}
+
+// advance steps the wheel forward to now, firing due timers and tickers
+// along the way, and returns the periods of any tickers that fired (for
+// SetWatchFunc callers). now must not be before w.now.
+//
+// now is always the clock's new absolute time, not a delta, and w.now
+// only ever moves forward by a whole number of resolutions, so any
+// fraction of a resolution that wasn't enough to make up a tick last call
+// is still sitting in the gap between w.now and now -- it doesn't need
+// its own carried-over field, and adding one in would double-count it.
+func (w *wheel) advance(now time.Time) (tickerPeriods []time.Duration) {
+	elapsed := now.Sub(w.now)
+	ticks := elapsed / w.resolution
+	for ; ticks > 0; ticks-- {
+		w.now = w.now.Add(w.resolution)
+		tickerPeriods = append(tickerPeriods, w.tick(now)...)
+	}
+	return tickerPeriods
+}
+
+// tick advances the cursor by a single resolution-sized step. It first
+// cascades every higher level whose cursor advances because the level
+// below it wrapped back to zero, reclassifying
+// those entries into the bucket their deadline now falls into, and only
+// then fires whatever ends up due in the level-0 bucket -- including
+// entries that an earlier level's cascade moved straight into it. Doing
+// the cascade before the fire in the same tick is what keeps an entry that
+// was clamped into a high level from firing a tick late once it finally
+// reaches level 0.
+func (w *wheel) tick(now time.Time) (tickerPeriods []time.Duration) {
+	// Level 0 always advances one slot; each higher level advances only
+	// when the level below it wraps back to zero. top ends up the highest
+	// level that advances this tick.
+	top := 0
+	for level := 0; level < len(w.cursor); level++ {
+		w.cursor[level] = (w.cursor[level] + 1) % w.size
+		top = level
+		if w.cursor[level] != 0 {
+			break
+		}
+	}
+
+	// Cascade from the highest advancing level down to level 1, so that an
+	// entry reclassified out of a high-level bucket is already sitting in
+	// the right lower-level bucket -- possibly level 0's -- by the time
+	// that bucket is drained.
+	for level := top; level >= 1; level-- {
+		entries := w.drain(&w.buckets[level][w.cursor[level]])
+		for _, e := range entries {
+			w.cascade(e)
+		}
+	}
+
+	entries := w.drain(&w.buckets[0][w.cursor[0]])
+	for _, e := range entries {
+		fired := e.wheelFire(now)
+		if t, ok := e.(*ticker); ok {
+			w.insert(t)
+			if fired {
+				tickerPeriods = append(tickerPeriods, t.d)
+			}
+		}
+	}
+	return tickerPeriods
+}
+
+// drain removes and returns every entry currently in bucket.
+func (w *wheel) drain(bucket *list.List) []wheelEntry {
+	if bucket.Len() == 0 {
+		return nil
+	}
+	entries := make([]wheelEntry, 0, bucket.Len())
+	for el := bucket.Front(); el != nil; el = el.Next() {
+		e := el.Value.(wheelEntry)
+		n := e.node()
+		n.inserted = false
+		n.elem = nil
+		entries = append(entries, e)
+	}
+	w.count -= len(entries)
+	bucket.Init()
+	return entries
+}